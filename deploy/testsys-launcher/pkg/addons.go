@@ -0,0 +1,113 @@
+package pkg
+
+import (
+	eks "github.com/aws/aws-cdk-go/awscdk/v2/awseks"
+	"github.com/aws/jsii-runtime-go"
+)
+
+const (
+	// The cert-manager Helm chart version required by testsys and brupop
+	certManagerChartVersion string = "v1.8.2"
+
+	// The brupop Helm chart version to keep the cluster up to date
+	brupopChartVersion string = "1.1.0"
+
+	// The testsys controller Helm chart version
+	testsysControllerChartVersion string = "0.1.0"
+)
+
+// Addon installs a piece of cluster-wide functionality (a controller,
+// operator, etc.) onto a testsys cluster via a Helm chart, rather than
+// applying raw YAML manifests at synth time.
+type Addon interface {
+	// Deploy installs the addon onto cluster and returns the resulting Helm
+	// chart construct so dependent addons can order themselves after it.
+	Deploy(cluster eks.ICluster) eks.HelmChart
+}
+
+// CertManagerAddon installs cert-manager, which brupop depends on to issue
+// the TLS certificates its webhooks need.
+type CertManagerAddon struct{}
+
+func (a CertManagerAddon) Deploy(cluster eks.ICluster) eks.HelmChart {
+	return cluster.AddHelmChart(jsii.String("cert-manager"), &eks.HelmChartOptions{
+		Chart:           jsii.String("cert-manager"),
+		Repository:      jsii.String("https://charts.jetstack.io"),
+		Release:         jsii.String("cert-manager"),
+		Namespace:       jsii.String("cert-manager"),
+		CreateNamespace: jsii.Bool(true),
+		Version:         jsii.String(certManagerChartVersion),
+		Values: &map[string]interface{}{
+			"installCRDs": true,
+		},
+	})
+}
+
+// BrupopAddon installs the Bottlerocket update operator, which keeps the
+// cluster's Bottlerocket nodes up to date. It requires cert-manager.
+type BrupopAddon struct{}
+
+func (a BrupopAddon) Deploy(cluster eks.ICluster) eks.HelmChart {
+	return cluster.AddHelmChart(jsii.String("brupop"), &eks.HelmChartOptions{
+		Chart:           jsii.String("bottlerocket-update-operator"),
+		Repository:      jsii.String("oci://public.ecr.aws/bottlerocket/bottlerocket-update-operator"),
+		Release:         jsii.String("brupop"),
+		Namespace:       jsii.String("brupop-bottlerocket-aws"),
+		CreateNamespace: jsii.Bool(true),
+		Version:         jsii.String(brupopChartVersion),
+	})
+}
+
+// TestsysAddon installs the testsys controller that watches and reconciles
+// testsys CRDs. The ECS/EKS provisioner and migration test runner agents it
+// spawns assume their own scoped IRSA role rather than the node role, so
+// their service account names and role ARNs are passed through as chart
+// values.
+type TestsysAddon struct {
+	ServiceAccountRoles *TestsysServiceAccountRoles
+}
+
+func (a TestsysAddon) Deploy(cluster eks.ICluster) eks.HelmChart {
+	return cluster.AddHelmChart(jsii.String("testsys-controller"), &eks.HelmChartOptions{
+		Chart:           jsii.String("testsys-controller"),
+		Repository:      jsii.String("oci://public.ecr.aws/bottlerocket-test-system/testsys-controller"),
+		Release:         jsii.String("testsys-controller"),
+		Namespace:       jsii.String("testsys-bottlerocket-aws"),
+		CreateNamespace: jsii.Bool(true),
+		Version:         jsii.String(testsysControllerChartVersion),
+		Values: &map[string]interface{}{
+			"serviceAccounts": map[string]interface{}{
+				"ecsProvisioner": map[string]interface{}{
+					"create":  false,
+					"name":    "ecs-provisioner",
+					"roleArn": *a.ServiceAccountRoles.EcsProvisionerRoleArn,
+				},
+				"eksProvisioner": map[string]interface{}{
+					"create":  false,
+					"name":    "eks-provisioner",
+					"roleArn": *a.ServiceAccountRoles.EksProvisionerRoleArn,
+				},
+				"migrationTestRunner": map[string]interface{}{
+					"create":  false,
+					"name":    "migration-test-runner",
+					"roleArn": *a.ServiceAccountRoles.MigrationRunnerRoleArn,
+				},
+			},
+		},
+	})
+}
+
+// DeployAddons installs cert-manager, brupop, and the testsys controller
+// onto cluster as pinned, version-controlled Helm releases, ordering brupop
+// and the testsys controller after cert-manager since both rely on the
+// webhook certificates it issues. saRoles are wired into the testsys
+// controller release so its agents assume their own scoped roles.
+func DeployAddons(cluster eks.ICluster, saRoles *TestsysServiceAccountRoles) {
+	certManager := CertManagerAddon{}.Deploy(cluster)
+
+	brupop := BrupopAddon{}.Deploy(cluster)
+	brupop.Node().AddDependency(certManager)
+
+	testsysController := TestsysAddon{ServiceAccountRoles: saRoles}.Deploy(cluster)
+	testsysController.Node().AddDependency(certManager)
+}
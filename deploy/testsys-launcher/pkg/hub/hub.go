@@ -0,0 +1,93 @@
+// Package hub supports running a testsys fleet -- many testsys clusters
+// across accounts and regions, driven from a single cdk deploy. It provides
+// the cross-account trust chain a central operator uses to reach every spoke
+// cluster's admin role, and the per-spoke connection parameters that make
+// that possible without direct credentials in any spoke account.
+package hub
+
+import (
+	"fmt"
+
+	awscdk "github.com/aws/aws-cdk-go/awscdk/v2"
+	eks "github.com/aws/aws-cdk-go/awscdk/v2/awseks"
+	iam "github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	ssm "github.com/aws/aws-cdk-go/awscdk/v2/awsssm"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// testsysAdminRoleName is the name every spoke's NewTestsysAdminUser role is
+// created with, so the hub operator role can derive its ARN from just the
+// spoke's account id.
+const testsysAdminRoleName string = "testsys-admin"
+
+// HubOperatorRoleName is the name NewHubOperatorRole creates its role with.
+// It's exported so callers can derive the role's ARN (account + partition
+// are known at the call site) without relying on its CDK token attribute,
+// since that attribute can't be resolved across the independent,
+// possibly-cross-account/partition stacks a fleet deploy produces.
+const HubOperatorRoleName string = "testsys-hub-operator"
+
+// SpokeCluster describes one testsys cluster in the fleet: the account and
+// region it's deployed into, and the parameters its launcher stack should
+// use.
+type SpokeCluster struct {
+	Account     string `json:"account"`
+	Region      string `json:"region"`
+	ClusterName string `json:"clusterName"`
+	// NodegroupSize is optional; when omitted, the spoke's launcher stack
+	// falls back to its own "TestsysNodegroupSize" CfnParameter default
+	// instead of deploying with no worker nodes.
+	NodegroupSize  *float64 `json:"nodegroupSize"`
+	AdminAssumedBy []string `json:"adminAssumedBy"`
+}
+
+// NewHubOperatorRole creates the "testsys-hub-operator" role a fleet
+// operator assumes in order to then assume into each spoke cluster's
+// testsys-admin role and dispatch testsys CRDs, without needing direct
+// credentials in any spoke account.
+func NewHubOperatorRole(stack awscdk.Stack, spokes []SpokeCluster) iam.Role {
+	partition := *awscdk.Stack_Of(stack).Partition()
+
+	spokeAdminArns := make([]*string, 0, len(spokes))
+	for _, spoke := range spokes {
+		spokeAdminArns = append(spokeAdminArns, jsii.String(
+			fmt.Sprintf("arn:%s:iam::%s:role/%s", partition, spoke.Account, testsysAdminRoleName),
+		))
+	}
+
+	operatorRole := iam.NewRole(stack, jsii.String("testsys-hub-operator"), &iam.RoleProps{
+		Description: jsii.String("Assumed by the fleet operator to dispatch testsys CRDs to every spoke cluster"),
+		AssumedBy:   iam.NewAccountRootPrincipal(),
+		RoleName:    jsii.String(HubOperatorRoleName),
+	})
+
+	operatorRole.AddToPolicy(iam.NewPolicyStatement(&iam.PolicyStatementProps{
+		Effect:    iam.Effect_ALLOW,
+		Actions:   &[]*string{jsii.String("sts:AssumeRole")},
+		Resources: &spokeAdminArns,
+	}))
+
+	return operatorRole
+}
+
+// NewSpokeConnectionParameters records a spoke cluster's endpoint and
+// certificate authority data in the spoke account's own SSM Parameter Store,
+// and grants adminRole permission to read them, so the fleet operator --
+// after assuming adminRole via NewHubOperatorRole's trust chain -- can fetch
+// a spoke's kubeconfig without ever needing standing credentials in the
+// spoke account.
+func NewSpokeConnectionParameters(stack awscdk.Stack, clusterName string, cluster eks.ICluster, adminRole iam.Role) {
+	parameterPrefix := fmt.Sprintf("/testsys/fleet/%s", clusterName)
+
+	endpointParam := ssm.NewStringParameter(stack, jsii.String("testsys-fleet-endpoint"), &ssm.StringParameterProps{
+		ParameterName: jsii.String(parameterPrefix + "/endpoint"),
+		StringValue:   cluster.ClusterEndpoint(),
+	})
+	endpointParam.GrantRead(adminRole)
+
+	caParam := ssm.NewStringParameter(stack, jsii.String("testsys-fleet-ca"), &ssm.StringParameterProps{
+		ParameterName: jsii.String(parameterPrefix + "/certificate-authority-data"),
+		StringValue:   cluster.ClusterCertificateAuthorityData(),
+	})
+	caParam.GrantRead(adminRole)
+}
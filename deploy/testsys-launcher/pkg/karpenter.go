@@ -0,0 +1,163 @@
+package pkg
+
+import (
+	"fmt"
+
+	awscdk "github.com/aws/aws-cdk-go/awscdk/v2"
+	eks "github.com/aws/aws-cdk-go/awscdk/v2/awseks"
+	iam "github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+const (
+	// The Karpenter Helm chart version to install
+	karpenterChartVersion string = "v0.27.3"
+
+	// The namespace Karpenter's controllers run in, and that its Fargate
+	// profile matches on so it can schedule before any EC2 nodes exist
+	karpenterNamespace string = "karpenter"
+
+	// karpenterDiscoveryTag is the tag Karpenter uses to discover which
+	// subnets and security groups it may launch nodes into
+	karpenterDiscoveryTag string = "karpenter.sh/discovery"
+)
+
+// NewKarpenterAddon installs Karpenter onto cluster and scopes it to testsys
+// workloads, so node capacity (instance type, architecture) can flex to what
+// a test run needs -- e.g. g4dn for GPU variants, m6g for arm64 Bottlerocket
+// -- instead of always paying for a fixed-size m5.xlarge managed nodegroup.
+func NewKarpenterAddon(stack constructs.Construct, cluster eks.Cluster, clusterName string) {
+	// Run Karpenter's own controllers on Fargate so it can provision the
+	// first EC2 nodes without needing EC2 nodes to already exist
+	cluster.AddFargateProfile(jsii.String("karpenter-fargate-profile"), &eks.FargateProfileOptions{
+		Selectors: &[]*eks.Selector{
+			{Namespace: jsii.String("kube-system")},
+			{Namespace: jsii.String(karpenterNamespace)},
+		},
+	})
+
+	controllerSa := cluster.AddServiceAccount(jsii.String("karpenter-controller"), &eks.ServiceAccountOptions{
+		Name:      jsii.String("karpenter"),
+		Namespace: jsii.String(karpenterNamespace),
+	})
+	controllerSa.AddToPrincipalPolicy(iam.NewPolicyStatement(&iam.PolicyStatementProps{
+		Effect: iam.Effect_ALLOW,
+		Actions: &[]*string{
+			// Permissions Karpenter needs to launch, tag, and terminate the
+			// instances it provisions
+			jsii.String("ec2:CreateLaunchTemplate"),
+			jsii.String("ec2:CreateFleet"),
+			jsii.String("ec2:RunInstances"),
+			jsii.String("ec2:CreateTags"),
+			jsii.String("ec2:TerminateInstances"),
+			jsii.String("ec2:DescribeLaunchTemplates"),
+			jsii.String("ec2:DescribeInstances"),
+			jsii.String("ec2:DescribeSecurityGroups"),
+			jsii.String("ec2:DescribeSubnets"),
+			jsii.String("ec2:DescribeInstanceTypes"),
+			jsii.String("ec2:DescribeInstanceTypeOfferings"),
+			jsii.String("ec2:DescribeAvailabilityZones"),
+			jsii.String("ec2:DescribeSpotPriceHistory"),
+
+			// So Karpenter can hand the KarpenterNode role to instances it launches
+			jsii.String("iam:PassRole"),
+
+			// Bottlerocket AMI id lookups
+			jsii.String("ssm:GetParameter"),
+
+			// On-demand/spot price lookups used to pick the cheapest instance type
+			jsii.String("pricing:GetProducts"),
+		},
+		Resources: &[]*string{
+			jsii.String("*"),
+		},
+	}))
+
+	nodeRole := iam.NewRole(stack, jsii.String("karpenter-node-role"), &iam.RoleProps{
+		Description: jsii.String("The role Karpenter-provisioned testsys nodes assume"),
+		AssumedBy:   iam.NewServicePrincipal(jsii.String(Ec2ServicePrincipalName(stack)), &iam.ServicePrincipalOpts{}),
+		RoleName:    jsii.String(fmt.Sprintf("%s-karpenter-node", clusterName)),
+	})
+	nodeRole.AddManagedPolicy(iam.ManagedPolicy_FromManagedPolicyArn(stack, jsii.String("karpenter-node-ecr-policy"), jsii.String(ManagedPolicyArn(stack, "AmazonEC2ContainerRegistryReadOnly"))))
+	nodeRole.AddManagedPolicy(iam.ManagedPolicy_FromManagedPolicyArn(stack, jsii.String("karpenter-node-cni-policy"), jsii.String(ManagedPolicyArn(stack, "AmazonEKS_CNI_Policy"))))
+	nodeRole.AddManagedPolicy(iam.ManagedPolicy_FromManagedPolicyArn(stack, jsii.String("karpenter-node-worker-policy"), jsii.String(ManagedPolicyArn(stack, "AmazonEKSWorkerNodePolicy"))))
+	nodeRole.AddManagedPolicy(iam.ManagedPolicy_FromManagedPolicyArn(stack, jsii.String("karpenter-node-ssm-policy"), jsii.String(ManagedPolicyArn(stack, "AmazonSSMManagedInstanceCore"))))
+
+	nodeInstanceProfile := iam.NewCfnInstanceProfile(stack, jsii.String("karpenter-node-instance-profile"), &iam.CfnInstanceProfileProps{
+		InstanceProfileName: jsii.String(fmt.Sprintf("%s-karpenter-node", clusterName)),
+		Roles:               &[]*string{nodeRole.RoleName()},
+	})
+
+	cluster.AwsAuth().AddRoleMapping(nodeRole, &eks.AwsAuthMapping{
+		Groups:   &[]*string{jsii.String("system:bootstrappers"), jsii.String("system:nodes")},
+		Username: jsii.String("system:node:{{EC2PrivateDNSName}}"),
+	})
+
+	for _, subnet := range *cluster.Vpc().PrivateSubnets() {
+		awscdk.Tags_Of(subnet).Add(jsii.String(karpenterDiscoveryTag), jsii.String(clusterName), &awscdk.TagProps{})
+	}
+	awscdk.Tags_Of(cluster.ClusterSecurityGroup()).Add(jsii.String(karpenterDiscoveryTag), jsii.String(clusterName), &awscdk.TagProps{})
+
+	karpenterChart := cluster.AddHelmChart(jsii.String("karpenter"), &eks.HelmChartOptions{
+		Chart:           jsii.String("karpenter"),
+		Repository:      jsii.String("oci://public.ecr.aws/karpenter/karpenter"),
+		Release:         jsii.String("karpenter"),
+		Namespace:       jsii.String(karpenterNamespace),
+		CreateNamespace: jsii.Bool(true),
+		Version:         jsii.String(karpenterChartVersion),
+		Values: &map[string]interface{}{
+			"serviceAccount": map[string]interface{}{
+				"create": false,
+				"name":   "karpenter",
+			},
+			"settings": map[string]interface{}{
+				"clusterName":     clusterName,
+				"clusterEndpoint": *cluster.ClusterEndpoint(),
+			},
+		},
+	})
+
+	// Scope the provisioner to the instance families testsys actually needs
+	// so Karpenter doesn't reach for anything exotic
+	provisioner := cluster.AddManifest(jsii.String("testsys-provisioner"), &map[string]interface{}{
+		"apiVersion": "karpenter.sh/v1alpha5",
+		"kind":       "Provisioner",
+		"metadata": map[string]interface{}{
+			"name": "testsys",
+		},
+		"spec": map[string]interface{}{
+			"requirements": []map[string]interface{}{
+				{
+					"key":      "karpenter.k8s.aws/instance-family",
+					"operator": "In",
+					"values":   []string{"m5", "m6g", "g4dn"},
+				},
+			},
+			"providerRef": map[string]interface{}{
+				"name": "testsys",
+			},
+			"ttlSecondsAfterEmpty": 30,
+		},
+	})
+	provisioner.Node().AddDependency(karpenterChart)
+
+	nodeTemplate := cluster.AddManifest(jsii.String("testsys-node-template"), &map[string]interface{}{
+		"apiVersion": "karpenter.k8s.aws/v1alpha1",
+		"kind":       "AWSNodeTemplate",
+		"metadata": map[string]interface{}{
+			"name": "testsys",
+		},
+		"spec": map[string]interface{}{
+			"amiFamily": "Bottlerocket",
+			"subnetSelector": map[string]interface{}{
+				karpenterDiscoveryTag: clusterName,
+			},
+			"securityGroupSelector": map[string]interface{}{
+				karpenterDiscoveryTag: clusterName,
+			},
+			"instanceProfile": *nodeInstanceProfile.InstanceProfileName(),
+		},
+	})
+	nodeTemplate.Node().AddDependency(karpenterChart)
+}
@@ -0,0 +1,27 @@
+package pkg
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/constructs-go/constructs/v10"
+)
+
+// ManagedPolicyArn builds the ARN of an AWS-managed IAM policy for whatever
+// partition scope is deployed into. AWS-managed policy ARNs differ outside
+// the commercial partition (e.g. "arn:aws-cn:..." in China, "arn:aws-us-gov:..."
+// in GovCloud), so callers should use this instead of hardcoding "arn:aws:...".
+func ManagedPolicyArn(scope constructs.Construct, policyName string) string {
+	partition := *awscdk.Stack_Of(scope).Partition()
+	return fmt.Sprintf("arn:%s:iam::aws:policy/%s", partition, policyName)
+}
+
+// Ec2ServicePrincipalName returns the EC2 service principal for whatever
+// partition scope is deployed into. It differs in the China partition
+// ("ec2.amazonaws.com.cn") from the rest of the commercial and GovCloud
+// partitions. The partition is usually an unresolved token at synth time, so
+// the name is built from the stack's URL suffix, which CloudFormation
+// resolves per partition at deploy time.
+func Ec2ServicePrincipalName(scope constructs.Construct) string {
+	return "ec2." + *awscdk.Stack_Of(scope).UrlSuffix()
+}
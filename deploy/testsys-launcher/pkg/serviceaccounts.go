@@ -0,0 +1,137 @@
+package pkg
+
+import (
+	"fmt"
+
+	awscdk "github.com/aws/aws-cdk-go/awscdk/v2"
+	eks "github.com/aws/aws-cdk-go/awscdk/v2/awseks"
+	iam "github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// TestsysServiceAccountRoles holds the IAM role ARNs for the Kubernetes
+// service accounts used by each testsys controller. Agents assume these
+// scoped roles via IRSA instead of inheriting the shared, node-wide
+// testsys-node-role.
+type TestsysServiceAccountRoles struct {
+	// EcsProvisionerRoleArn is assumed by the ECS resource provisioner agent.
+	EcsProvisionerRoleArn *string
+	// EksProvisionerRoleArn is assumed by the EKS/eksctl resource provisioner agent.
+	EksProvisionerRoleArn *string
+	// MigrationRunnerRoleArn is assumed by the Bottlerocket migration test runner agent.
+	MigrationRunnerRoleArn *string
+}
+
+// NewTestsysServiceAccountRoles creates one IAM role per testsys controller,
+// each trusted by the cluster's OIDC provider (via cluster.AddServiceAccount)
+// and bound to a dedicated Kubernetes service account in namespace, scoped to
+// just the permissions that controller needs.
+func NewTestsysServiceAccountRoles(stack constructs.Construct, cluster eks.ICluster, namespace string) *TestsysServiceAccountRoles {
+	ecsProvisioner := cluster.AddServiceAccount(jsii.String("ecs-provisioner"), &eks.ServiceAccountOptions{
+		Name:      jsii.String("ecs-provisioner"),
+		Namespace: jsii.String(namespace),
+	})
+	ecsProvisioner.AddToPrincipalPolicy(iam.NewPolicyStatement(&iam.PolicyStatementProps{
+		Effect: iam.Effect_ALLOW,
+		Actions: &[]*string{
+			// ECS permissions so the ECS provisioner can manage and provision
+			// ECS variant tests and clusters
+			jsii.String("ecs:CreateCluster"),
+			jsii.String("ecs:DeleteCluster"),
+			jsii.String("ecs:DeregisterContainerInstance"),
+			jsii.String("ecs:DescribeClusters"),
+			jsii.String("ecs:DescribeTaskDefinition"),
+			jsii.String("ecs:DescribeTasks"),
+			jsii.String("ecs:DiscoverPollEndpoint"),
+			jsii.String("ecs:ListContainerInstances"),
+			jsii.String("ecs:ListTaskDefinitions"),
+			jsii.String("ecs:RegisterContainerInstance"),
+			jsii.String("ecs:RunTask"),
+			jsii.String("ecs:SubmitTaskStateChange"),
+		},
+		Resources: &[]*string{
+			jsii.String("*"),
+		},
+	}))
+
+	eksProvisioner := cluster.AddServiceAccount(jsii.String("eks-provisioner"), &eks.ServiceAccountOptions{
+		Name:      jsii.String("eks-provisioner"),
+		Namespace: jsii.String(namespace),
+	})
+	eksProvisioner.AddToPrincipalPolicy(iam.NewPolicyStatement(&iam.PolicyStatementProps{
+		Effect: iam.Effect_ALLOW,
+		Actions: &[]*string{
+			// EKS all access permissions so the EKS/eksctl provisioner can
+			// create, delete, and tag clusters it stands up for tests
+			jsii.String("eks:*"),
+
+			// IAM permissions so the provisioner can manage roles for
+			// resources that it creates (like k8s clusters through eksctl)
+			jsii.String("iam:AddRoleToInstanceProfile"),
+			jsii.String("iam:AttachRolePolicy"),
+			jsii.String("iam:CreateInstanceProfile"),
+			jsii.String("iam:CreateOpenIDConnectProvider"),
+			jsii.String("iam:CreateRole"),
+			jsii.String("iam:DeleteInstanceProfile"),
+			jsii.String("iam:DeleteOpenIDConnectProvider"),
+			jsii.String("iam:DeleteRole"),
+			jsii.String("iam:DeleteRolePolicy"),
+			jsii.String("iam:DetachRolePolicy"),
+			jsii.String("iam:GetInstanceProfile"),
+			jsii.String("iam:GetOpenIDConnectProvider"),
+			jsii.String("iam:GetRole"),
+			jsii.String("iam:GetRolePolicy"),
+			jsii.String("iam:ListInstanceProfilesForRole"),
+			jsii.String("iam:PassRole"),
+			jsii.String("iam:PutRolePolicy"),
+			jsii.String("iam:RemoveRoleFromInstanceProfile"),
+
+			// Aws sts permissions
+			jsii.String("sts:GetCallerIdentity"),
+		},
+		Resources: &[]*string{
+			jsii.String("*"),
+		},
+	}))
+
+	migrationRunner := cluster.AddServiceAccount(jsii.String("migration-test-runner"), &eks.ServiceAccountOptions{
+		Name:      jsii.String("migration-test-runner"),
+		Namespace: jsii.String(namespace),
+	})
+	migrationRunner.AddToPrincipalPolicy(iam.NewPolicyStatement(&iam.PolicyStatementProps{
+		Effect: iam.Effect_ALLOW,
+		Actions: &[]*string{
+			// SSM permissions so the migration test runner can drive
+			// Bottlerocket updates on the instances under test
+			jsii.String("ssm:SendCommand"),
+			jsii.String("ssm:GetCommandInvocation"),
+			jsii.String("ssm:ListCommandInvocations"),
+		},
+		Resources: &[]*string{
+			jsii.String("*"),
+		},
+	}))
+	migrationRunner.AddToPrincipalPolicy(iam.NewPolicyStatement(&iam.PolicyStatementProps{
+		Effect: iam.Effect_ALLOW,
+		Actions: &[]*string{
+			// The migration test runner may assume only roles in this
+			// stack's own account whose names start with
+			// "testsys-migration-target-", not arbitrary roles.
+			jsii.String("sts:AssumeRole"),
+		},
+		Resources: &[]*string{
+			jsii.String(fmt.Sprintf(
+				"arn:%s:iam::%s:role/testsys-migration-target-*",
+				*awscdk.Stack_Of(stack).Partition(),
+				*awscdk.Stack_Of(stack).Account(),
+			)),
+		},
+	}))
+
+	return &TestsysServiceAccountRoles{
+		EcsProvisionerRoleArn:  ecsProvisioner.Role().RoleArn(),
+		EksProvisionerRoleArn:  eksProvisioner.Role().RoleArn(),
+		MigrationRunnerRoleArn: migrationRunner.Role().RoleArn(),
+	}
+}
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
 	"strings"
 	"testsys-launcher/pkg"
+	"testsys-launcher/pkg/hub"
 
 	"github.com/aws/aws-cdk-go/awscdk/v2"
 	ec2 "github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
@@ -15,14 +20,116 @@ import (
 
 type TestsysLauncherStackProps struct {
 	awscdk.StackProps
+
+	// ClusterName overrides the name of the testsys EKS cluster. Defaults to
+	// "testsys", or to the "clusterName" context value, if empty. Set by the
+	// fleet launcher in main so each spoke cluster gets a distinct name.
+	ClusterName string
+	// NodegroupSize overrides the minimum nodegroup size normally supplied by
+	// the "TestsysNodegroupSize" CfnParameter.
+	NodegroupSize *float64
+	// AdminAssumedBy overrides the principals allowed to assume the cluster's
+	// "testsys-admin" role, normally supplied by the "testsysAdminAssumedBy"
+	// context value. Entries are role names in this stack's own account,
+	// except for a full IAM role ARN, which is trusted as-is -- this is how
+	// a fleet hub's operator role (in a different account) is granted access.
+	AdminAssumedBy []string
+}
+
+// ExistingClusterOptions describes a pre-existing EKS cluster that testsys
+// should be deployed onto instead of provisioning a new control plane.
+type ExistingClusterOptions struct {
+	// ClusterName is the name of the existing EKS cluster.
+	ClusterName string
+	// KubectlRoleArn is the ARN of an IAM role with enough permissions to run
+	// kubectl commands against the cluster.
+	KubectlRoleArn string
+	// OidcProviderArn is the ARN of the cluster's IAM OIDC identity provider.
+	OidcProviderArn string
+	// VpcId is the id of the VPC the cluster's nodes run in.
+	VpcId string
+	// ClusterEndpoint and ClusterCertificateAuthorityData record this
+	// cluster's connection details so the fleet hub can reach it (see
+	// hub.NewSpokeConnectionParameters). CDK's imported-cluster attributes
+	// can't be looked up the way a newly-created cluster's can, so these are
+	// left empty -- and connection parameters skipped -- unless supplied.
+	ClusterEndpoint                 string
+	ClusterCertificateAuthorityData string
+}
+
+// hasConnectionParameters reports whether existing has enough information to
+// record spoke connection parameters for the fleet hub. A newly-created
+// cluster always does; an imported one only does if they were supplied,
+// since CDK can't otherwise derive them without a live lookup.
+func (existing *ExistingClusterOptions) hasConnectionParameters() bool {
+	return existing == nil || (existing.ClusterEndpoint != "" && existing.ClusterCertificateAuthorityData != "")
+}
+
+// contextString reads a string value from the stack's CDK context, returning
+// the empty string if it isn't set.
+func contextString(stack awscdk.Stack, key string) string {
+	if v := stack.Node().TryGetContext(jsii.String(key)); v != nil {
+		return v.(string)
+	}
+	return ""
+}
+
+// contextBool reads a boolean value from the stack's CDK context, returning
+// false if it isn't set. Context passed via "cdk deploy -c key=value" always
+// arrives as a string, so this parses it rather than asserting a Go bool.
+func contextBool(stack awscdk.Stack, key string) bool {
+	v := contextString(stack, key)
+	if v == "" {
+		return false
+	}
+	enabled, err := strconv.ParseBool(v)
+	if err != nil {
+		panic(fmt.Sprintf("context value %q for %q must be a boolean: %v", v, key, err))
+	}
+	return enabled
+}
+
+// importTestsysCluster imports a pre-existing EKS cluster so that testsys can
+// be deployed onto it alongside the workloads it already runs.
+func importTestsysCluster(stack constructs.Construct, existing *ExistingClusterOptions) eks.ICluster {
+	vpc := ec2.Vpc_FromLookup(stack, jsii.String("testsys-vpc"), &ec2.VpcLookupOptions{
+		VpcId: jsii.String(existing.VpcId),
+	})
+
+	oidcProvider := iam.OpenIdConnectProvider_FromOpenIdConnectProviderArn(
+		stack, jsii.String("testsys-oidc-provider"), jsii.String(existing.OidcProviderArn),
+	)
+
+	attrs := &eks.ClusterAttributes{
+		ClusterName:           jsii.String(existing.ClusterName),
+		KubectlRoleArn:        jsii.String(existing.KubectlRoleArn),
+		OpenIdConnectProvider: oidcProvider,
+		Vpc:                   vpc,
+	}
+	if existing.ClusterEndpoint != "" {
+		attrs.ClusterEndpoint = jsii.String(existing.ClusterEndpoint)
+	}
+	if existing.ClusterCertificateAuthorityData != "" {
+		attrs.ClusterCertificateAuthorityData = jsii.String(existing.ClusterCertificateAuthorityData)
+	}
+
+	return eks.Cluster_FromClusterAttributes(stack, jsii.String("testsys"), attrs)
 }
 
-// NewTestsysCluster creates a new EKS 1.25 cluster with the default capacity
-// set to 0 and a custom managed nodegroup using bottlerocket AMIs
-func NewTestsysCluster(stack constructs.Construct, size float64) eks.Cluster {
+// NewTestsysCluster creates a new EKS 1.25 cluster named clusterName with the
+// default capacity set to 0. If existing is non-nil, testsys is instead
+// deployed onto the EKS cluster it describes rather than provisioning a new
+// one. Otherwise, node capacity comes from either a fixed managed nodegroup
+// sized by size, or, if karpenter is true, dynamically-provisioned Karpenter
+// nodes scoped to testsys workloads.
+func NewTestsysCluster(stack constructs.Construct, size float64, existing *ExistingClusterOptions, karpenter bool, clusterName string) eks.ICluster {
+	if existing != nil {
+		return importTestsysCluster(stack, existing)
+	}
+
 	testsysClusterProps := eks.ClusterProps{
 		Version:     eks.KubernetesVersion_V1_25(),
-		ClusterName: jsii.String("testsys"),
+		ClusterName: jsii.String(clusterName),
 		// This kubectl layer is a lambda layer that can run commands (like
 		// applying manifests) for us via the CDK stack
 		KubectlLayer: kubectlLayer.NewKubectlLayer(stack, jsii.String("kubectl-lambda-layer")),
@@ -34,6 +141,13 @@ func NewTestsysCluster(stack constructs.Construct, size float64) eks.Cluster {
 	// Create the testsys cluster using defined properties
 	testsysCluster := eks.NewCluster(stack, jsii.String("testsys"), &testsysClusterProps)
 
+	if karpenter {
+		// Let Karpenter size nodes to whatever the test run needs instead of
+		// paying for a fixed-size nodegroup between test campaigns
+		pkg.NewKarpenterAddon(stack, testsysCluster, clusterName)
+		return testsysCluster
+	}
+
 	// Create the role that EC2 nodes can assume
 	nodeRole := pkg.NewTestSysNodeRole(stack, "testsys-node-role")
 
@@ -51,16 +165,27 @@ func NewTestsysCluster(stack constructs.Construct, size float64) eks.Cluster {
 }
 
 // NewTestsysAdminUser creates a new "testsys-admin" role and adds it to the
-// "masters" list in the Kubernetes cluster aws-auth config map.
-// This role can be assumed by the "roleName" that gets passed in.
-func NewTestsysAdminUser(stack constructs.Construct, c eks.Cluster, roleNames []string) {
+// "masters" list in the Kubernetes cluster aws-auth config map. This role
+// can be assumed by each principal in roleNames: a bare name resolves to a
+// role in this stack's own account, while a full IAM role ARN is trusted
+// as-is, which is how a principal in another account (e.g. a fleet hub's
+// operator role) is granted access.
+func NewTestsysAdminUser(stack constructs.Construct, c eks.ICluster, roleNames []string) iam.Role {
 	adminRoleOptions := &iam.FromRoleNameOptions{
 		AddGrantsToResources: jsii.Bool(false),
 		DefaultPolicyName:    jsii.String("defaultPolicyName"),
 		Mutable:              jsii.Bool(false),
 	}
 	var roles []iam.IPrincipal
-	for _, name := range roleNames {
+	for i, name := range roleNames {
+		if strings.HasPrefix(name, "arn:") {
+			roles = append(roles, iam.Role_FromRoleArn(
+				stack, jsii.String(fmt.Sprintf("admin-principal-%d", i)), jsii.String(name), &iam.FromRoleArnOptions{
+					Mutable: jsii.Bool(false),
+				},
+			))
+			continue
+		}
 		roles = append(roles, iam.Role_FromRoleName(stack, jsii.String(name), jsii.String(name), adminRoleOptions))
 	}
 
@@ -71,9 +196,13 @@ func NewTestsysAdminUser(stack constructs.Construct, c eks.Cluster, roleNames []
 	})
 
 	c.AwsAuth().AddMastersRole(adminRole, jsii.String("admin"))
+
+	return adminRole
 }
 
-// NewTestsysLauncherStack deploys the entire testsys stack
+// NewTestsysLauncherStack deploys a single testsys cluster, which is one
+// spoke of the fleet when launched via a fleet config (see main), or the
+// whole deployment when launched on its own.
 func NewTestsysLauncherStack(scope constructs.Construct, id string, props *TestsysLauncherStackProps) awscdk.Stack {
 	var sprops awscdk.StackProps
 	if props != nil {
@@ -82,32 +211,172 @@ func NewTestsysLauncherStack(scope constructs.Construct, id string, props *Tests
 	stack := awscdk.NewStack(scope, &id, &sprops)
 
 	// Parameters
-	var testsysAdminAssumedByContext string = stack.Node().TryGetContext(jsii.String("testsysAdminAssumedBy")).(string)
-	testsysAdminAssumedBy := strings.Split(testsysAdminAssumedByContext, ",")
+	clusterName := "testsys"
+	if props != nil && props.ClusterName != "" {
+		clusterName = props.ClusterName
+	} else if v := contextString(stack, "clusterName"); v != "" {
+		clusterName = v
+	}
+
+	var testsysAdminAssumedBy []string
+	if props != nil && len(props.AdminAssumedBy) > 0 {
+		testsysAdminAssumedBy = props.AdminAssumedBy
+	} else {
+		testsysAdminAssumedBy = strings.Split(contextString(stack, "testsysAdminAssumedBy"), ",")
+	}
 
 	testsysNodegroupSize := awscdk.NewCfnParameter(stack, jsii.String("TestsysNodegroupSize"), &awscdk.CfnParameterProps{
 		Type:        jsii.String("Number"),
 		Description: jsii.String("The minimum size of the testsys nodegroup"),
 		Default:     jsii.Number(3),
 	})
+	nodegroupSize := *testsysNodegroupSize.ValueAsNumber()
+	if props != nil && props.NodegroupSize != nil {
+		nodegroupSize = *props.NodegroupSize
+	}
+
+	// An existing EKS cluster can be imported instead of provisioning a new
+	// one by setting "existingClusterName" (and its related context values).
+	var existingCluster *ExistingClusterOptions
+	if existingClusterName := contextString(stack, "existingClusterName"); existingClusterName != "" {
+		existingCluster = &ExistingClusterOptions{
+			ClusterName:                     existingClusterName,
+			KubectlRoleArn:                  contextString(stack, "existingClusterKubectlRoleArn"),
+			OidcProviderArn:                 contextString(stack, "existingClusterOidcProviderArn"),
+			VpcId:                           contextString(stack, "existingClusterVpcId"),
+			ClusterEndpoint:                 contextString(stack, "existingClusterEndpoint"),
+			ClusterCertificateAuthorityData: contextString(stack, "existingClusterCertificateAuthorityData"),
+		}
+	}
+
+	// Karpenter-based dynamic node provisioning is opt-in via context, as an
+	// alternative to the fixed managed nodegroup
+	karpenterEnabled := contextBool(stack, "karpenterEnabled")
 
 	// Start testsys deployments
-	testsysCluster := NewTestsysCluster(stack, *testsysNodegroupSize.ValueAsNumber())
-	NewTestsysAdminUser(stack, testsysCluster, testsysAdminAssumedBy)
+	testsysCluster := NewTestsysCluster(stack, nodegroupSize, existingCluster, karpenterEnabled, clusterName)
+	adminRole := NewTestsysAdminUser(stack, testsysCluster, testsysAdminAssumedBy)
+
+	// Give each testsys controller its own IRSA role, scoped to just the
+	// permissions that controller needs, instead of the node role
+	saRoles := pkg.NewTestsysServiceAccountRoles(stack, testsysCluster, "testsys")
+
+	// Install cert-manager, brupop, and the testsys controller as pinned
+	// Helm releases instead of applying raw YAML manifests at synth time.
+	// The controller release is wired up with saRoles so its agents assume
+	// their own scoped roles.
+	pkg.DeployAddons(testsysCluster, saRoles)
+
+	awscdk.NewCfnOutput(stack, jsii.String("EcsProvisionerRoleArn"), &awscdk.CfnOutputProps{
+		Value: saRoles.EcsProvisionerRoleArn,
+	})
+	awscdk.NewCfnOutput(stack, jsii.String("EksProvisionerRoleArn"), &awscdk.CfnOutputProps{
+		Value: saRoles.EksProvisionerRoleArn,
+	})
+	awscdk.NewCfnOutput(stack, jsii.String("MigrationRunnerRoleArn"), &awscdk.CfnOutputProps{
+		Value: saRoles.MigrationRunnerRoleArn,
+	})
+
+	// Record this cluster's connection details so the fleet hub's operator
+	// role can retrieve them after assuming adminRole. Skipped for an
+	// imported cluster that didn't supply them, since CDK's imported-cluster
+	// endpoint/CA getters panic at synth time otherwise.
+	if existingCluster.hasConnectionParameters() {
+		hub.NewSpokeConnectionParameters(stack, clusterName, testsysCluster, adminRole)
+	}
 
 	return stack
 }
 
+// loadFleetConfig reads the fleet config file at path -- a JSON array of
+// spoke cluster descriptions -- used to stand up a multi-cluster,
+// multi-account testsys fleet from a single "cdk deploy".
+func loadFleetConfig(path string) ([]hub.SpokeCluster, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var spokes []hub.SpokeCluster
+	if err := json.Unmarshal(data, &spokes); err != nil {
+		return nil, err
+	}
+
+	return spokes, nil
+}
+
 func main() {
 	defer jsii.Close()
 
 	app := awscdk.NewApp(nil)
 
-	NewTestsysLauncherStack(app, "TestsysLauncherStack", &TestsysLauncherStackProps{
-		awscdk.StackProps{
-			Env: env(),
-		},
-	})
+	// A fleet config file turns this from a single-cluster launcher into a
+	// multi-cluster, multi-account one: one separate, top-level launcher
+	// stack per spoke cluster (not nested stacks of the hub stack), plus a
+	// "hub" stack with the cross-account operator role that can reach every
+	// spoke.
+	var fleetConfigPath string
+	if v := app.Node().TryGetContext(jsii.String("fleetConfigPath")); v != nil {
+		fleetConfigPath = v.(string)
+	}
+
+	if fleetConfigPath == "" {
+		NewTestsysLauncherStack(app, "TestsysLauncherStack", &TestsysLauncherStackProps{
+			StackProps: awscdk.StackProps{
+				Env: env(),
+			},
+		})
+	} else {
+		spokes, err := loadFleetConfig(fleetConfigPath)
+		if err != nil {
+			panic(fmt.Sprintf("could not load fleet config %q: %v", fleetConfigPath, err))
+		}
+
+		// The hub account/partition must be known at synth time so each
+		// spoke can literally trust the hub operator role's ARN -- it's a
+		// cross-account reference, so it can't be resolved via a CDK token.
+		var hubAccount string
+		if v := app.Node().TryGetContext(jsii.String("hubAccount")); v != nil {
+			hubAccount = v.(string)
+		}
+		hubPartition := "aws"
+		if v := app.Node().TryGetContext(jsii.String("hubPartition")); v != nil {
+			hubPartition = v.(string)
+		}
+		if hubAccount == "" {
+			panic("fleetConfigPath requires the \"hubAccount\" context value to be set")
+		}
+
+		hubStack := awscdk.NewStack(app, jsii.String("TestsysHubStack"), &awscdk.StackProps{
+			Env: &awscdk.Environment{
+				Account: jsii.String(hubAccount),
+			},
+		})
+		hub.NewHubOperatorRole(hubStack, spokes)
+		hubOperatorRoleArn := fmt.Sprintf("arn:%s:iam::%s:role/%s", hubPartition, hubAccount, hub.HubOperatorRoleName)
+
+		for _, spoke := range spokes {
+			spoke := spoke
+			// Every spoke trusts the hub operator role in addition to
+			// whatever principals its own config lists, so the operator can
+			// assume into this spoke's "testsys-admin" role too.
+			adminAssumedBy := append(append([]string{}, spoke.AdminAssumedBy...), hubOperatorRoleArn)
+			NewTestsysLauncherStack(app, fmt.Sprintf("TestsysLauncherStack-%s", spoke.ClusterName), &TestsysLauncherStackProps{
+				StackProps: awscdk.StackProps{
+					Env: &awscdk.Environment{
+						Account: jsii.String(spoke.Account),
+						Region:  jsii.String(spoke.Region),
+					},
+				},
+				ClusterName: spoke.ClusterName,
+				// nil unless the fleet config set it, so an omitted
+				// nodegroupSize falls back to the launcher stack's own
+				// CfnParameter default instead of deploying with MinSize: 0.
+				NodegroupSize:  spoke.NodegroupSize,
+				AdminAssumedBy: adminAssumedBy,
+			})
+		}
+	}
 
 	app.Synth(nil)
 }